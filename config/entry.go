@@ -0,0 +1,28 @@
+package config
+
+// Entry is a single sd-local configuration entry, keyed by SD_API_URL in the
+// user's config file.
+type Entry struct {
+	APIURL   string   `yaml:"api_url"`
+	StoreURL string   `yaml:"store_url"`
+	Launcher Launcher `yaml:"launcher"`
+	Registry Registry `yaml:"registry"`
+}
+
+// Launcher configures which launcher image sd-local uses to run builds.
+type Launcher struct {
+	Image   string `yaml:"image"`
+	Version string `yaml:"version"`
+}
+
+// Registry configures mirrors and retry behavior for image pulls, read from
+// the "registry" block of an Entry.
+type Registry struct {
+	// Mirrors are tried, in order, before falling back to an image's own registry.
+	Mirrors []string `yaml:"mirrors"`
+	// Policy is one of "always" (default), "missing" or "never".
+	Policy string `yaml:"policy"`
+	// Retries is the number of additional pull attempts per mirror after a failed
+	// pull. Every failure is retried; transient and permanent errors are not told apart.
+	Retries int `yaml:"retries"`
+}
@@ -0,0 +1,410 @@
+package launch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ArtifactsDir is default artifact directory name
+	ArtifactsDir = "sd-artifacts"
+	// LogFile is default logfile name for build log
+	LogFile = "builds.log"
+	// The definition of "ScmHost" and "OrgRepo" is in "PipelineFromID" of "screwdriver/screwdriver_local.go"
+	scmHost = "screwdriver.cd"
+	orgRepo = "sd-local/local-build"
+)
+
+// cliRunner implements runner on top of a docker-compatible CLI. docker and
+// podman only differ in which binary they shell out to, which binary and
+// subcommand build a local image, and whether commands need a sudo prefix, so both newDocker
+// and newPodman construct one of these instead of keeping separate types.
+type cliRunner struct {
+	binary            string // "docker" or "podman"
+	buildBinary       string // binary that builds a local image: "docker" or "buildah"
+	buildVerb         string // subcommand that builds a local image: "build" or "bud"
+	useSudo           bool
+	volume            string
+	habVolume         string
+	setupImage        string
+	setupImageVersion string
+	commands          []*exec.Cmd
+	mutex             *sync.Mutex
+	flagVerbose       bool
+	registry          RegistryConfig
+	logSink           LogSink
+}
+
+var _ runner = (*cliRunner)(nil)
+var execCommand = exec.Command
+
+func newCLIRunner(binary, buildBinary, buildVerb, setupImage, setupImageVer string, useSudo, flagVerbose bool, registry RegistryConfig, logSink LogSink) *cliRunner {
+	return &cliRunner{
+		binary:            binary,
+		buildBinary:       buildBinary,
+		buildVerb:         buildVerb,
+		useSudo:           useSudo,
+		volume:            "SD_LAUNCH_BIN",
+		habVolume:         "SD_LAUNCH_HAB",
+		setupImage:        setupImage,
+		setupImageVersion: setupImageVer,
+		commands:          make([]*exec.Cmd, 0, 10),
+		mutex:             &sync.Mutex{},
+		flagVerbose:       flagVerbose,
+		registry:          registry,
+		logSink:           logSink,
+	}
+}
+
+func (r *cliRunner) setupBin() error {
+	err := r.execCommand("volume", "create", "--name", r.volume)
+	if err != nil {
+		return fmt.Errorf("failed to create %s volume: %v", r.binary, err)
+	}
+
+	err = r.execCommand("volume", "create", "--name", r.habVolume)
+	if err != nil {
+		return fmt.Errorf("failed to create %s hab volume: %v", r.binary, err)
+	}
+
+	mount := fmt.Sprintf("%s:/opt/sd/", r.volume)
+	habMount := fmt.Sprintf("%s:/hab", r.habVolume)
+	image := fmt.Sprintf("%s:%s", r.setupImage, r.setupImageVersion)
+	err = r.pullImage(image)
+	if err != nil {
+		return fmt.Errorf("failed to pull launcher image: %v", err)
+	}
+
+	err = r.execCommand("container", "run", "--rm", "-v", mount, "-v", habMount, image, "--entrypoint", "/bin/echo set up bin")
+	if err != nil {
+		return fmt.Errorf("failed to prepare build scripts: %v", err)
+	}
+
+	return nil
+}
+
+func (r *cliRunner) runBuild(buildEntry buildEntry) error {
+	environment := buildEntry.Environment[0]
+
+	srcDir := buildEntry.SrcPath
+	hostArtDir := buildEntry.ArtifactsPath
+	containerArtDir := environment["SD_ARTIFACTS_DIR"]
+	buildImage := buildEntry.Image
+	logfilePath := filepath.Join(containerArtDir, LogFile)
+
+	selinuxSuffix := selinuxLabelSuffix(buildEntry.SELinuxLabel)
+
+	srcVol := fmt.Sprintf("%s/:/sd/workspace/src/%s/%s%s", srcDir, scmHost, orgRepo, selinuxSuffix)
+	artVol := fmt.Sprintf("%s/:%s%s", hostArtDir, containerArtDir, selinuxSuffix)
+	binVol := fmt.Sprintf("%s:%s%s", r.volume, "/opt/sd", selinuxSuffix)
+	habVol := fmt.Sprintf("%s:%s%s", r.habVolume, "/opt/sd/hab", selinuxSuffix)
+	configJSON, err := json.Marshal(buildEntry)
+	if err != nil {
+		return err
+	}
+
+	if isLocalDockerfile(buildImage) {
+		buildImage, err = r.buildLocalImage(buildEntry)
+		if err != nil {
+			return err
+		}
+	} else {
+		logrus.Infof("Pulling %s image from %s...", r.binary, buildImage)
+		err = r.pullImage(buildImage)
+		if err != nil {
+			return fmt.Errorf("failed to pull user image %v", err)
+		}
+	}
+
+	commandArgs := []string{"container", "run"}
+	commandOptions := []string{"--rm", "-v", srcVol, "-v", artVol, "-v", binVol, "-v", habVol, buildImage, "/opt/sd/local_run.sh", string(configJSON), buildEntry.JobName, environment["SD_API_URL"], environment["SD_STORE_URL"], logfilePath}
+
+	if buildEntry.MemoryLimit != "" {
+		commandOptions = append([]string{fmt.Sprintf("-m%s", buildEntry.MemoryLimit)}, commandOptions...)
+	}
+
+	if buildEntry.UsePrivileged {
+		commandOptions = append([]string{"--privileged"}, commandOptions...)
+	}
+
+	err = r.runJobContainer(append(commandArgs, commandOptions...), buildEntry.JobName)
+	if err != nil {
+		return fmt.Errorf("failed to run build container: %v", err)
+	}
+
+	return nil
+}
+
+// runJobContainer runs the job container. When r.logSink is configured, its
+// stdout/stderr are streamed live and parsed into structured events instead
+// of only landing in builds.log and, under flagVerbose, logrus.
+func (r *cliRunner) runJobContainer(args []string, jobName string) error {
+	commands := append([]string{r.binary}, args...)
+	if r.useSudo {
+		commands = append([]string{"sudo"}, commands...)
+	}
+
+	cmd := execCommand(commands[0], commands[1:]...)
+	if r.flagVerbose {
+		logrus.Infof("$ %s", strings.Join(commands, " "))
+	}
+	r.commands = append(r.commands, cmd)
+
+	if r.logSink == nil {
+		if r.flagVerbose {
+			cmd.Stdout = logrus.StandardLogger().WriterLevel(logrus.InfoLevel)
+		}
+		buf := bytes.NewBuffer(nil)
+		cmd.Stderr = buf
+		err := cmd.Run()
+		if err != nil {
+			io.Copy(os.Stderr, buf)
+			return err
+		}
+		return nil
+	}
+
+	return streamToSink(cmd, jobName, r.logSink)
+}
+
+// pullImage fetches image according to r.registry's pull policy, trying each
+// configured mirror in order (with retries) before falling back to image's
+// own registry.
+func (r *cliRunner) pullImage(image string) error {
+	switch r.registry.Policy {
+	case PullNever:
+		return nil
+	case PullMissing:
+		if r.imageExists(image) {
+			return nil
+		}
+	}
+
+	var err error
+	for _, ref := range mirrorRefs(image, r.registry.Mirrors) {
+		ref := ref
+		err = retryWithBackoff(r.registry.Retries, func() error {
+			return r.execCommand("pull", ref)
+		})
+		if err == nil {
+			if ref != image {
+				return r.execCommand("tag", ref, image)
+			}
+			return nil
+		}
+	}
+
+	return err
+}
+
+// imageExists reports whether image is already present locally. A non-zero
+// exit here just means "not cached yet", which is the expected outcome the
+// first time PullMissing sees a given image, so unlike execCommand it never
+// surfaces the command's stderr as if it were a real failure.
+func (r *cliRunner) imageExists(image string) bool {
+	commands := []string{r.binary, "image", "inspect", image}
+	if r.useSudo {
+		commands = append([]string{"sudo"}, commands...)
+	}
+
+	cmd := execCommand(commands[0], commands[1:]...)
+	r.commands = append(r.commands, cmd)
+
+	return cmd.Run() == nil
+}
+
+// buildLocalImageArgs builds the argument list for the build command. ref is
+// the resolved Dockerfile/build-context path and isFile reports whether it
+// names a file rather than a directory: when it does, the file's directory
+// becomes the build context and "-f ref" selects it as the Dockerfile;
+// otherwise ref itself is the context and the builder looks for a Dockerfile
+// inside it.
+func buildLocalImageArgs(buildVerb, tag, ref string, isFile bool, buildArgs map[string]string) []string {
+	context := ref
+	args := []string{buildVerb, "-t", tag}
+
+	if isFile {
+		context = filepath.Dir(ref)
+		args = append(args, "-f", ref)
+	}
+
+	for k, v := range buildArgs {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	args = append(args, context)
+
+	return args
+}
+
+// buildLocalImage builds the job image from the Dockerfile or build context
+// referenced by buildEntry.Image and returns the resulting tag to run.
+func (r *cliRunner) buildLocalImage(buildEntry buildEntry) (string, error) {
+	ref := strings.TrimPrefix(buildEntry.Image, localImageScheme)
+	tag := fmt.Sprintf("sd-local/%s:%s", buildEntry.JobName, buildEntry.Sha)
+
+	info, err := os.Stat(ref)
+	isFile := err == nil && !info.IsDir()
+	args := buildLocalImageArgs(r.buildVerb, tag, ref, isFile, buildEntry.BuildArgs)
+
+	logrus.Infof("Building local image %s from %s...", tag, ref)
+
+	commands := append([]string{r.buildBinary}, args...)
+	if r.useSudo {
+		commands = append([]string{"sudo"}, commands...)
+	}
+	cmd := execCommand(commands[0], commands[1:]...)
+	if r.flagVerbose {
+		logrus.Infof("$ %s", strings.Join(commands, " "))
+		cmd.Stdout = logrus.StandardLogger().WriterLevel(logrus.InfoLevel)
+	}
+	cmd.Stderr = logrus.StandardLogger().WriterLevel(logrus.ErrorLevel)
+	r.commands = append(r.commands, cmd)
+	buf := bytes.NewBuffer(nil)
+	cmd.Stderr = buf
+	if err := cmd.Run(); err != nil {
+		io.Copy(os.Stderr, buf)
+		return "", fmt.Errorf("failed to build local image: %v", err)
+	}
+
+	return tag, nil
+}
+
+// selinuxLabelSuffix returns the bind-mount suffix for the given SELinux
+// label mode: ":z" shares the label across containers, ":Z" relabels the
+// mount private to this container. An empty label leaves the mount as-is.
+func selinuxLabelSuffix(label string) string {
+	switch label {
+	case "shared":
+		return ":z"
+	case "private":
+		return ":Z"
+	default:
+		return ""
+	}
+}
+
+func (r *cliRunner) execCommand(args ...string) error {
+	commands := append([]string{r.binary}, args...)
+	if r.useSudo {
+		commands = append([]string{"sudo"}, commands...)
+	}
+	cmd := execCommand(commands[0], commands[1:]...)
+	if r.flagVerbose {
+		logrus.Infof("$ %s", strings.Join(commands, " "))
+		cmd.Stdout = logrus.StandardLogger().WriterLevel(logrus.InfoLevel)
+	}
+	cmd.Stderr = logrus.StandardLogger().WriterLevel(logrus.ErrorLevel)
+	r.commands = append(r.commands, cmd)
+	buf := bytes.NewBuffer(nil)
+	cmd.Stderr = buf
+	err := cmd.Run()
+	if err != nil {
+		io.Copy(os.Stderr, buf)
+		return err
+	}
+	return nil
+}
+
+func (r *cliRunner) kill(sig os.Signal) {
+	killedCmds := make([]*exec.Cmd, 0, 10)
+
+	for _, v := range r.commands {
+		var err error
+		r.mutex.Lock()
+		if v.ProcessState != nil {
+			continue
+		}
+		r.mutex.Unlock()
+
+		if r.useSudo {
+			cmd := execCommand("sudo", "kill", fmt.Sprintf("-%v", signum(sig)), strconv.Itoa(v.Process.Pid))
+			err = cmd.Run()
+		} else {
+			err = v.Process.Signal(sig)
+		}
+
+		if err != nil {
+			logrus.Warn(fmt.Errorf("failed to stop process: %v", err))
+		} else {
+			killedCmds = append(killedCmds, v)
+		}
+	}
+
+	err := r.waitForProcess(killedCmds)
+	if err != nil {
+		logrus.Warn(err)
+	}
+}
+
+func (r *cliRunner) clean() {
+	err := r.execCommand("volume", "rm", "--force", r.volume)
+
+	if err != nil {
+		logrus.Warn(fmt.Errorf("failed to remove volume: %v", err))
+	}
+
+	err = r.execCommand("volume", "rm", "--force", r.habVolume)
+
+	if err != nil {
+		logrus.Warn(fmt.Errorf("failed to remove hab volume: %v", err))
+	}
+}
+
+func (r *cliRunner) waitForProcess(cmds []*exec.Cmd) error {
+	// Reducing this value will make the test faster.
+	// However, be sure to specify a time when you can sufficiently confirm that the process is dead.
+	t := time.NewTicker(1 * time.Second)
+	const retryMax = 9
+	retryCnt := 0
+	for {
+		select {
+		case <-t.C:
+
+			retryCnt++
+			finish := true
+
+			for _, v := range cmds {
+				r.mutex.Lock()
+				if v.ProcessState == nil {
+					finish = false
+				}
+				r.mutex.Unlock()
+			}
+			if finish {
+				return nil
+			}
+
+			if retryCnt > retryMax {
+				return fmt.Errorf("waited %d seconds and could not confirm that the process was dead", retryMax+1)
+			}
+		}
+	}
+}
+
+func signum(sig os.Signal) int {
+	const numSig = 65
+
+	switch sig := sig.(type) {
+	case syscall.Signal:
+		i := int(sig)
+		if i < 0 || i >= numSig {
+			return -1
+		}
+		return i
+	default:
+		return -1
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 
 	"github.com/screwdriver-cd/sd-local/config"
 	"github.com/screwdriver-cd/sd-local/screwdriver"
@@ -37,6 +38,7 @@ var _ (Launcher) = (*launch)(nil)
 type launch struct {
 	buildEntry buildEntry
 	runner     runner
+	runtime    string
 }
 
 // EnvVar is a map for environment variables
@@ -61,6 +63,8 @@ type buildEntry struct {
 	SrcPath       string             `json:"-"`
 	UseSudo       bool               `json:"-"`
 	UsePrivileged bool               `json:"-"`
+	SELinuxLabel  string             `json:"-"`
+	BuildArgs     EnvVar             `json:"-"`
 }
 
 // Option is option for launch New
@@ -77,12 +81,54 @@ type Option struct {
 	UseSudo       bool
 	UsePrivileged bool
 	FlagVerbose   bool
+	Runtime       string
+	// SELinuxLabel is the SELinux relabeling mode for bind mounts.
+	// One of "" (auto-detect), "shared" (:z) or "private" (:Z).
+	SELinuxLabel string
+	// BuildArgs are passed as --build-arg when Job.Image is a local Dockerfile.
+	BuildArgs EnvVar
+	// LogSink receives structured build log events as the build container
+	// runs. When nil, the runner falls back to its historic logrus output.
+	LogSink LogSink
 }
 
 const (
 	defaultArtDir = "/sd/workspace/artifacts"
+
+	// RuntimeDocker runs builds through the docker CLI
+	RuntimeDocker = "docker"
+	// RuntimePodman runs builds through the rootless podman CLI
+	RuntimePodman = "podman"
+
+	// localImageScheme marks a job image as a local Dockerfile or build
+	// context to build before running, instead of a pre-published registry image.
+	localImageScheme = "dockerfile://"
 )
 
+// selinuxEnforceFile is a var, not a const, so tests can point it at a fake
+// file instead of the real host SELinux state.
+var selinuxEnforceFile = "/sys/fs/selinux/enforce"
+
+func isLocalDockerfile(image string) bool {
+	return strings.HasPrefix(image, localImageScheme)
+}
+
+// resolveSELinuxLabel returns the explicit label if set, otherwise it
+// auto-detects SELinux enforcement on the host and defaults to a shared
+// (:z) label so bind-mounted source and artifacts directories stay readable.
+func resolveSELinuxLabel(label string) string {
+	if label != "" {
+		return label
+	}
+
+	enforce, err := os.ReadFile(selinuxEnforceFile)
+	if err != nil || strings.TrimSpace(string(enforce)) != "1" {
+		return ""
+	}
+
+	return "shared"
+}
+
 func mergeEnv(env, jobEnv, optionEnv EnvVar) []EnvVar {
 	for k, v := range jobEnv {
 		env[k] = v
@@ -138,6 +184,8 @@ func createBuildEntry(option Option) buildEntry {
 		SrcPath:       option.SrcPath,
 		UseSudo:       option.UseSudo,
 		UsePrivileged: option.UsePrivileged,
+		SELinuxLabel:  resolveSELinuxLabel(option.SELinuxLabel),
+		BuildArgs:     option.BuildArgs,
 	}
 }
 
@@ -145,7 +193,19 @@ func createBuildEntry(option Option) buildEntry {
 func New(option Option) Launcher {
 	l := new(launch)
 
-	l.runner = newDocker(option.Entry.Launcher.Image, option.Entry.Launcher.Version, option.UseSudo, option.FlagVerbose)
+	l.runtime = option.Runtime
+	if l.runtime == "" {
+		l.runtime = RuntimeDocker
+	}
+
+	registry := registryConfigFromEntry(option.Entry.Registry)
+
+	switch l.runtime {
+	case RuntimePodman:
+		l.runner = newPodman(option.Entry.Launcher.Image, option.Entry.Launcher.Version, option.FlagVerbose, registry, option.LogSink)
+	default:
+		l.runner = newDocker(option.Entry.Launcher.Image, option.Entry.Launcher.Version, option.UseSudo, option.FlagVerbose, registry, option.LogSink)
+	}
 	l.buildEntry = createBuildEntry(option)
 
 	return l
@@ -153,8 +213,8 @@ func New(option Option) Launcher {
 
 // Run runs the build specified.
 func (l *launch) Run() error {
-	if _, err := lookPath("docker"); err != nil {
-		return fmt.Errorf("`docker` command is not found in $PATH: %v", err)
+	if _, err := lookPath(l.runtime); err != nil {
+		return fmt.Errorf("`%s` command is not found in $PATH: %v", l.runtime, err)
 	}
 
 	if err := l.runner.setupBin(); err != nil {
@@ -0,0 +1,53 @@
+package launch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsLocalDockerfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  bool
+	}{
+		{name: "dockerfile scheme", image: "dockerfile://./Dockerfile", want: true},
+		{name: "registry image", image: "node:14", want: false},
+		{name: "registry image with host", image: "registry.example.com/someorg/image:tag", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isLocalDockerfile(tt.image)
+			if got != tt.want {
+				t.Errorf("isLocalDockerfile(%q) = %v, want %v", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildLocalImageArgs(t *testing.T) {
+	t.Run("directory context builds without -f", func(t *testing.T) {
+		got := buildLocalImageArgs("build", "sd-local/main:sha", "/repo", false, nil)
+		want := []string{"build", "-t", "sd-local/main:sha", "/repo"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("buildLocalImageArgs = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("file context passes -f and uses the file's directory", func(t *testing.T) {
+		got := buildLocalImageArgs("bud", "sd-local/main:sha", "/repo/Dockerfile.custom", true, nil)
+		want := []string{"bud", "-t", "sd-local/main:sha", "-f", "/repo/Dockerfile.custom", "/repo"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("buildLocalImageArgs = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("build args are formatted as --build-arg KEY=VALUE", func(t *testing.T) {
+		got := buildLocalImageArgs("build", "sd-local/main:sha", "/repo", false, map[string]string{"VERSION": "1.2.3"})
+		want := []string{"build", "-t", "sd-local/main:sha", "--build-arg", "VERSION=1.2.3", "/repo"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("buildLocalImageArgs = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package launch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event types emitted on a LogSink while a build container runs.
+const (
+	LogEventStdout    = "stdout"
+	LogEventStderr    = "stderr"
+	LogEventStepStart = "step_start"
+	LogEventStepEnd   = "step_end"
+	LogEventBuildEnd  = "build_end"
+)
+
+// LogEvent is a single structured event parsed out of a running build
+// container's output.
+type LogEvent struct {
+	Type     string        `json:"type"`
+	Job      string        `json:"job"`
+	Step     string        `json:"step,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	ExitCode int           `json:"exitCode,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// LogSink receives structured build log events as a build runs. When unset
+// on Option, the runner falls back to its historic behavior: stdout goes to
+// logrus only under flagVerbose, and stderr is buffered and copied to
+// os.Stderr only when the container exits with an error.
+type LogSink interface {
+	Handle(event LogEvent)
+}
+
+// The screwdriver launcher brackets each step with these markers on stdout:
+// "[SD_STEP_START] <step>" and "[SD_STEP_END] <step> <exitCode>".
+const (
+	stepStartPrefix = "[SD_STEP_START]"
+	stepEndPrefix   = "[SD_STEP_END]"
+)
+
+func parseStepStart(line string) (step string, ok bool) {
+	if !strings.HasPrefix(line, stepStartPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, stepStartPrefix)), true
+}
+
+func parseStepEnd(line string) (step string, exitCode int, ok bool) {
+	if !strings.HasPrefix(line, stepEndPrefix) {
+		return "", 0, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, stepEndPrefix))
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+	exitCode, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], exitCode, true
+}
+
+// streamToSink runs cmd, scanning its stdout and stderr line by line and
+// forwarding each as a structured event to sink, and returns once the
+// container exits.
+func streamToSink(cmd *exec.Cmd, jobName string, sink LogSink) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go scanToSink(stdout, LogEventStdout, jobName, sink, &wg)
+	go scanToSink(stderr, LogEventStderr, jobName, sink, &wg)
+	wg.Wait()
+
+	err = cmd.Wait()
+
+	exitCode := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+	sink.Handle(LogEvent{Type: LogEventBuildEnd, Job: jobName, ExitCode: exitCode})
+
+	return err
+}
+
+// maxLogLine raises the scanner's token limit well past its 64KB default so
+// a single long line (minified/base64 step output) doesn't silently cut the
+// stream off partway through a build.
+const maxLogLine = 1024 * 1024
+
+func scanToSink(r io.Reader, stream, jobName string, sink LogSink, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	var step string
+	var stepStarted time.Time
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if name, ok := parseStepStart(line); ok {
+			step = name
+			stepStarted = time.Now()
+			sink.Handle(LogEvent{Type: LogEventStepStart, Job: jobName, Step: step})
+			continue
+		}
+
+		if name, exitCode, ok := parseStepEnd(line); ok {
+			sink.Handle(LogEvent{Type: LogEventStepEnd, Job: jobName, Step: name, ExitCode: exitCode, Duration: time.Since(stepStarted)})
+			continue
+		}
+
+		sink.Handle(LogEvent{Type: stream, Job: jobName, Step: step, Message: line})
+	}
+
+	if err := scanner.Err(); err != nil {
+		logrus.Warn(fmt.Errorf("failed to read %s: %v", stream, err))
+	}
+}
+
+type ttyLogSink struct {
+	out   io.Writer
+	mutex *sync.Mutex
+}
+
+// NewTTYLogSink returns a LogSink that writes colorized, human-readable
+// events to out.
+func NewTTYLogSink(out io.Writer) LogSink {
+	return &ttyLogSink{out: out, mutex: &sync.Mutex{}}
+}
+
+// Handle is called concurrently from the stdout- and stderr-scanning
+// goroutines in streamToSink, so writes to out must be serialized.
+func (s *ttyLogSink) Handle(event LogEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch event.Type {
+	case LogEventStepStart:
+		fmt.Fprintf(s.out, "\x1b[36m==> %s\x1b[0m\n", event.Step)
+	case LogEventStepEnd:
+		color := "\x1b[32m"
+		if event.ExitCode != 0 {
+			color = "\x1b[31m"
+		}
+		fmt.Fprintf(s.out, "%s<== %s (exit %d, %s)\x1b[0m\n", color, event.Step, event.ExitCode, event.Duration.Round(time.Millisecond))
+	case LogEventBuildEnd:
+		fmt.Fprintf(s.out, "\x1b[1mbuild finished (exit %d)\x1b[0m\n", event.ExitCode)
+	case LogEventStderr:
+		fmt.Fprintf(s.out, "\x1b[31m%s\x1b[0m\n", event.Message)
+	default:
+		fmt.Fprintln(s.out, event.Message)
+	}
+}
+
+type jsonlLogSink struct {
+	out   io.Writer
+	mutex *sync.Mutex
+}
+
+// NewJSONLLogSink returns a LogSink that writes each event as a line of JSON
+// to out, suitable for machine consumption (editors, CI wrappers).
+func NewJSONLLogSink(out io.Writer) LogSink {
+	return &jsonlLogSink{out: out, mutex: &sync.Mutex{}}
+}
+
+func (s *jsonlLogSink) Handle(event LogEvent) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		logrus.Warn(fmt.Errorf("failed to marshal log event: %v", err))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fmt.Fprintln(s.out, string(b))
+}
@@ -0,0 +1,49 @@
+package launch
+
+import "testing"
+
+func TestParseStepStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantStep string
+		wantOK   bool
+	}{
+		{name: "well formed marker", line: "[SD_STEP_START] install", wantStep: "install", wantOK: true},
+		{name: "not a marker", line: "npm install", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := parseStepStart(tt.line)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("parseStepStart(%q) = (%q, %v), want (%q, %v)", tt.line, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseStepEnd(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		wantStep     string
+		wantExitCode int
+		wantOK       bool
+	}{
+		{name: "well formed marker", line: "[SD_STEP_END] install 0", wantStep: "install", wantExitCode: 0, wantOK: true},
+		{name: "non-zero exit code", line: "[SD_STEP_END] test 1", wantStep: "test", wantExitCode: 1, wantOK: true},
+		{name: "missing exit code", line: "[SD_STEP_END] install", wantOK: false},
+		{name: "non-numeric exit code", line: "[SD_STEP_END] install zero", wantOK: false},
+		{name: "not a marker", line: "npm install", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, exitCode, ok := parseStepEnd(tt.line)
+			if ok != tt.wantOK || step != tt.wantStep || exitCode != tt.wantExitCode {
+				t.Errorf("parseStepEnd(%q) = (%q, %d, %v), want (%q, %d, %v)", tt.line, step, exitCode, ok, tt.wantStep, tt.wantExitCode, tt.wantOK)
+			}
+		})
+	}
+}
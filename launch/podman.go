@@ -0,0 +1,7 @@
+package launch
+
+// newPodman creates a runner which runs builds through podman in rootless
+// mode, building local images with buildah (podman's build tool).
+func newPodman(setupImage, setupImageVer string, flagVerbose bool, registry RegistryConfig, logSink LogSink) runner {
+	return newCLIRunner("podman", "buildah", "bud", setupImage, setupImageVer, false, flagVerbose, registry, logSink)
+}
@@ -0,0 +1,117 @@
+package launch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/screwdriver-cd/sd-local/config"
+	"github.com/sirupsen/logrus"
+)
+
+// PullPolicy controls when a runner fetches an image before using it.
+type PullPolicy string
+
+const (
+	// PullAlways always pulls, even if the image already exists locally.
+	PullAlways PullPolicy = "always"
+	// PullMissing only pulls when the image is not already present locally.
+	PullMissing PullPolicy = "missing"
+	// PullNever never pulls; the image must already exist locally.
+	PullNever PullPolicy = "never"
+)
+
+// RegistryConfig configures registry mirrors and retry behavior for image
+// pulls: the launcher image in setupBin and the job image in runBuild.
+type RegistryConfig struct {
+	// Mirrors are tried, in order, before falling back to the image's own registry.
+	Mirrors []string
+	// Policy is one of PullAlways (default), PullMissing or PullNever.
+	Policy PullPolicy
+	// Retries is the number of additional pull attempts per mirror after a failed
+	// pull. Every failure is retried; transient and permanent errors are not told apart.
+	Retries int
+}
+
+const defaultPullBackoff = 500 * time.Millisecond
+
+// registryConfigFromEntry converts the user-facing config.Entry.Registry
+// block into the RegistryConfig the runners act on. An unset Policy defaults
+// to PullAlways, matching docker's own default pull behavior; an unrecognized
+// Policy also falls back to PullAlways, but is logged so a typo in the user's
+// config doesn't silently change pull behavior.
+func registryConfigFromEntry(entry config.Registry) RegistryConfig {
+	policy := PullPolicy(entry.Policy)
+	switch policy {
+	case PullAlways, PullMissing, PullNever:
+	case "":
+		policy = PullAlways
+	default:
+		logrus.Warnf("unrecognized registry policy %q, falling back to %q", entry.Policy, PullAlways)
+		policy = PullAlways
+	}
+
+	return RegistryConfig{
+		Mirrors: entry.Mirrors,
+		Policy:  policy,
+		Retries: entry.Retries,
+	}
+}
+
+// mirrorRefs rewrites image's registry host against each configured mirror,
+// in priority order, and appends the original reference as the final
+// fallback. Images with no registry host (e.g. "node:14") implicitly refer
+// to Docker Hub, exactly like the "docker pull" CLI treats them, so they are
+// rewritten too rather than skipped.
+func mirrorRefs(image string, mirrors []string) []string {
+	rest := stripRegistryHost(image)
+	refs := make([]string, 0, len(mirrors)+1)
+
+	for _, mirror := range mirrors {
+		refs = append(refs, fmt.Sprintf("%s/%s", strings.TrimSuffix(mirror, "/"), rest))
+	}
+
+	refs = append(refs, image)
+
+	return refs
+}
+
+// stripRegistryHost returns the path a mirror host should be prefixed onto:
+// the repository/tag with the host stripped off, for a reference that names
+// an explicit registry host (following the same rule docker uses: the first
+// path segment is a host only if it contains a "." or ":", or is
+// "localhost"); the reference unchanged, for one that already names an
+// organization on the implicit Docker Hub (e.g. "someorg/image:tag"); or the
+// reference prefixed with "library/", for a bare official image (e.g.
+// "node:14" -> "library/node:14"), matching how docker.io resolves it.
+func stripRegistryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) != 2 {
+		return "library/" + image
+	}
+
+	first := parts[0]
+	if first == "localhost" || strings.ContainsAny(first, ".:") {
+		return parts[1]
+	}
+
+	return image
+}
+
+// retryWithBackoff calls fn until it succeeds or retries is exhausted,
+// doubling the delay between attempts.
+func retryWithBackoff(retries int, fn func() error) error {
+	backoff := defaultPullBackoff
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		logrus.Warnf("pull attempt %d/%d failed: %v; retrying in %s", attempt+1, retries+1, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
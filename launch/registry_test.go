@@ -0,0 +1,133 @@
+package launch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/screwdriver-cd/sd-local/config"
+)
+
+func TestStripRegistryHost(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{name: "bare official image", image: "node:14", want: "library/node:14"},
+		{name: "bare image without tag", image: "alpine", want: "library/alpine"},
+		{name: "docker hub org/repo", image: "someorg/image:tag", want: "someorg/image:tag"},
+		{name: "explicit host with dot", image: "registry.example.com/someorg/image:tag", want: "someorg/image:tag"},
+		{name: "explicit host with port", image: "localhost:5000/image:tag", want: "image:tag"},
+		{name: "localhost without port", image: "localhost/image:tag", want: "image:tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripRegistryHost(tt.image)
+			if got != tt.want {
+				t.Errorf("stripRegistryHost(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrorRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		mirrors []string
+		want    []string
+	}{
+		{
+			name:    "bare image is rewritten against the mirror",
+			image:   "node:14",
+			mirrors: []string{"mirror.example.com"},
+			want:    []string{"mirror.example.com/library/node:14", "node:14"},
+		},
+		{
+			name:    "explicit host image is rewritten against each mirror in order",
+			image:   "registry.example.com/someorg/image:tag",
+			mirrors: []string{"mirror-a.example.com", "mirror-b.example.com/"},
+			want: []string{
+				"mirror-a.example.com/someorg/image:tag",
+				"mirror-b.example.com/someorg/image:tag",
+				"registry.example.com/someorg/image:tag",
+			},
+		},
+		{
+			name:    "no mirrors configured falls back to the original reference",
+			image:   "node:14",
+			mirrors: nil,
+			want:    []string{"node:14"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mirrorRefs(tt.image, tt.mirrors)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mirrorRefs(%q, %v) = %v, want %v", tt.image, tt.mirrors, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	t.Run("succeeds without retrying", func(t *testing.T) {
+		calls := 0
+		err := retryWithBackoff(3, func() error {
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+
+	t.Run("retries up to the configured count then gives up", func(t *testing.T) {
+		wantErr := errors.New("transient failure")
+		calls := 0
+		err := retryWithBackoff(2, func() error {
+			calls++
+			return wantErr
+		})
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("err = %v, want %v", err, wantErr)
+		}
+		if calls != 3 {
+			t.Errorf("calls = %d, want 3 (1 initial + 2 retries)", calls)
+		}
+	})
+}
+
+func TestRegistryConfigFromEntry(t *testing.T) {
+	t.Run("unset policy defaults to always", func(t *testing.T) {
+		got := registryConfigFromEntry(config.Registry{})
+		if got.Policy != PullAlways {
+			t.Errorf("Policy = %q, want default %q", got.Policy, PullAlways)
+		}
+	})
+
+	t.Run("explicit fields pass through", func(t *testing.T) {
+		got := registryConfigFromEntry(config.Registry{
+			Mirrors: []string{"mirror.example.com"},
+			Policy:  "missing",
+			Retries: 5,
+		})
+		want := RegistryConfig{Mirrors: []string{"mirror.example.com"}, Policy: PullMissing, Retries: 5}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("registryConfigFromEntry = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("unrecognized policy falls back to always", func(t *testing.T) {
+		got := registryConfigFromEntry(config.Registry{Policy: "neve"})
+		if got.Policy != PullAlways {
+			t.Errorf("Policy = %q, want fallback %q", got.Policy, PullAlways)
+		}
+	})
+}
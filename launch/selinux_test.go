@@ -0,0 +1,71 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSELinuxLabel(t *testing.T) {
+	t.Run("explicit label passes through untouched", func(t *testing.T) {
+		got := resolveSELinuxLabel("private")
+		if got != "private" {
+			t.Errorf("resolveSELinuxLabel(%q) = %q, want %q", "private", got, "private")
+		}
+	})
+
+	origFile := selinuxEnforceFile
+	defer func() { selinuxEnforceFile = origFile }()
+
+	tests := []struct {
+		name     string
+		contents string
+		writeErr bool
+		want     string
+	}{
+		{name: "enforcing host defaults to shared", contents: "1", want: "shared"},
+		{name: "permissive host defaults to unset", contents: "0", want: ""},
+		{name: "missing enforce file defaults to unset", writeErr: true, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.writeErr {
+				selinuxEnforceFile = filepath.Join(t.TempDir(), "does-not-exist")
+			} else {
+				path := filepath.Join(t.TempDir(), "enforce")
+				if err := os.WriteFile(path, []byte(tt.contents), 0644); err != nil {
+					t.Fatalf("failed to write fake enforce file: %v", err)
+				}
+				selinuxEnforceFile = path
+			}
+
+			got := resolveSELinuxLabel("")
+			if got != tt.want {
+				t.Errorf("resolveSELinuxLabel(\"\") = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSELinuxLabelSuffix(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		want  string
+	}{
+		{name: "shared label", label: "shared", want: ":z"},
+		{name: "private label", label: "private", want: ":Z"},
+		{name: "unset label", label: "", want: ""},
+		{name: "unrecognized label", label: "bogus", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selinuxLabelSuffix(tt.label)
+			if got != tt.want {
+				t.Errorf("selinuxLabelSuffix(%q) = %q, want %q", tt.label, got, tt.want)
+			}
+		})
+	}
+}